@@ -0,0 +1,77 @@
+package builder
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ReleaseManifest is the subset of a BOSH release.MF that kiln cares
+// about when identifying a compiled release tarball.
+type ReleaseManifest struct {
+	Name            string `yaml:"name"`
+	Version         string `yaml:"version"`
+	StemcellOS      string `yaml:"compiled_package_stemcell_os,omitempty"`
+	StemcellVersion string `yaml:"compiled_package_stemcell_version,omitempty"`
+}
+
+// ReleaseManifestReader extracts the release.MF from a release tarball.
+type ReleaseManifestReader interface {
+	Read(path string) (ReleaseManifest, error)
+}
+
+type releaseManifestReader struct{}
+
+// NewReleaseManifestReader returns a ReleaseManifestReader that reads
+// release.MF out of a gzipped tar release.
+func NewReleaseManifestReader() ReleaseManifestReader {
+	return releaseManifestReader{}
+}
+
+func (r releaseManifestReader) Read(path string) (ReleaseManifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ReleaseManifest{}, err
+	}
+	defer file.Close()
+
+	gzr, err := gzip.NewReader(file)
+	if err != nil {
+		return ReleaseManifest{}, fmt.Errorf("could not read %s as a gzipped tarball: %w", path, err)
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ReleaseManifest{}, err
+		}
+
+		if header.Name != "release.MF" {
+			continue
+		}
+
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return ReleaseManifest{}, err
+		}
+
+		var manifest ReleaseManifest
+		if err := yaml.Unmarshal(contents, &manifest); err != nil {
+			return ReleaseManifest{}, err
+		}
+
+		return manifest, nil
+	}
+
+	return ReleaseManifest{}, fmt.Errorf("%s does not contain a release.MF", path)
+}
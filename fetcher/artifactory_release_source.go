@@ -0,0 +1,142 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+// ArtifactoryReleaseSource lists and downloads compiled releases from a
+// generic HTTP/Artifactory repository, addressed by config.Bucket used
+// as the repository's base URL.
+type ArtifactoryReleaseSource struct {
+	config cargo.ReleaseSourceConfig
+	regex  *CompiledReleasesRegexp
+	client *http.Client
+
+	mu   sync.Mutex
+	keys map[cargo.CompiledRelease]string
+}
+
+// NewArtifactoryReleaseSource returns an ArtifactoryReleaseSource for
+// the given Kilnfile sources: entry.
+func NewArtifactoryReleaseSource(config cargo.ReleaseSourceConfig, regex *CompiledReleasesRegexp) *ArtifactoryReleaseSource {
+	return &ArtifactoryReleaseSource{
+		config: config,
+		regex:  regex,
+		client: http.DefaultClient,
+		keys:   map[cargo.CompiledRelease]string{},
+	}
+}
+
+func (a *ArtifactoryReleaseSource) Name() string { return "artifactory:" + a.config.Bucket }
+func (a *ArtifactoryReleaseSource) Host() string { return a.config.Bucket }
+
+// artifactoryFileList is the subset of Artifactory's file list API
+// (GET /api/storage/{repo}?list&deep=1) that kiln cares about.
+type artifactoryFileList struct {
+	Files []struct {
+		URI string `xml:"uri"`
+	} `xml:"file"`
+}
+
+// List enumerates every file in the repository whose path matches the
+// source's path_template.
+func (a *ArtifactoryReleaseSource) List(ctx context.Context) ([]cargo.CompiledRelease, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(a.config.Bucket, "/")+"?list&deep=1", nil)
+	if err != nil {
+		return nil, err
+	}
+	if a.config.Username != "" {
+		request.SetBasicAuth(a.config.Username, a.config.Password)
+	}
+
+	response, err := a.client.Do(request)
+	if err != nil {
+		return nil, classifyTimeout(fmt.Errorf("failed to list files in %q: %w", a.config.Bucket, err))
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		return nil, MarkRetryable(fmt.Errorf("failed to list files in %q: unexpected status %s", a.config.Bucket, response.Status))
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list files in %q: unexpected status %s", a.config.Bucket, response.Status)
+	}
+
+	var list artifactoryFileList
+	if err := xml.NewDecoder(response.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to parse file list from %q: %w", a.config.Bucket, err)
+	}
+
+	var releases []cargo.CompiledRelease
+	for _, file := range list.Files {
+		key := strings.TrimPrefix(file.URI, "/")
+
+		release, err := a.regex.Convert(key)
+		if err != nil {
+			continue
+		}
+		releases = append(releases, release)
+
+		a.mu.Lock()
+		a.keys[release] = key
+		a.mu.Unlock()
+	}
+
+	return releases, nil
+}
+
+// Download fetches release from the repository into releasesDir.
+// release must have come from a prior call to List.
+func (a *ArtifactoryReleaseSource) Download(ctx context.Context, releasesDir string, release cargo.CompiledRelease) (string, int64, error) {
+	a.mu.Lock()
+	key, ok := a.keys[release]
+	a.mu.Unlock()
+	if !ok {
+		return "", 0, fmt.Errorf("release %s %s was not found by a prior List call", release.Name, release.Version)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(a.config.Bucket, "/")+"/"+key, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	if a.config.Username != "" {
+		request.SetBasicAuth(a.config.Username, a.config.Password)
+	}
+
+	response, err := a.client.Do(request)
+	if err != nil {
+		return "", 0, classifyTimeout(fmt.Errorf("failed to download %s: %w", key, err))
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		return "", 0, MarkRetryable(fmt.Errorf("failed to download %s: unexpected status %s", key, response.Status))
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("failed to download %s: unexpected status %s", key, response.Status)
+	}
+
+	path := filepath.Join(releasesDir, filepath.Base(key))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	bytesWritten, err := io.Copy(file, response.Body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return path, bytesWritten, nil
+}
@@ -0,0 +1,127 @@
+package fetcher_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/builder"
+	"github.com/pivotal-cf/kiln/fetcher"
+	"github.com/pivotal-cf/kiln/internal/baking"
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+var _ = Describe("ArtifactoryReleaseSource", func() {
+	var (
+		server      *httptest.Server
+		releasesDir string
+		regex       *fetcher.CompiledReleasesRegexp
+	)
+
+	BeforeEach(func() {
+		var err error
+		regex, err = fetcher.NewCompiledReleasesRegexp(`^(?P<release_name>[a-z-_]+)-(?P<release_version>[0-9\.]+)-(?P<stemcell_os>[a-z-_]+)-(?P<stemcell_version>[\d\.]+)\.tgz$`)
+		Expect(err).NotTo(HaveOccurred())
+
+		releasesDir, err = ioutil.TempDir("", "releases")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(releasesDir)
+		if server != nil {
+			server.Close()
+		}
+	})
+
+	Describe("List", func() {
+		It("converts the file list into compiled releases, skipping entries that don't match path_template", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`<list>
+					<file><uri>/some-release-1.2.3-ubuntu-xenial-190.0.0.tgz</uri></file>
+					<file><uri>/not-a-release.txt</uri></file>
+				</list>`))
+			}))
+
+			source := fetcher.NewArtifactoryReleaseSource(cargo.ReleaseSourceConfig{Type: "artifactory", Bucket: server.URL}, regex)
+
+			releases, err := source.List(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(releases).To(ConsistOf(cargo.CompiledRelease{
+				Name: "some-release", Version: "1.2.3", StemcellOS: "ubuntu-xenial", StemcellVersion: "190.0.0",
+			}))
+		})
+
+		It("returns a non-retryable error for a non-5xx, non-200 status", func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			}))
+
+			source := fetcher.NewArtifactoryReleaseSource(cargo.ReleaseSourceConfig{Type: "artifactory", Bucket: server.URL}, regex)
+
+			_, err := source.List(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("404"))
+		})
+	})
+
+	Describe("Download", func() {
+		It("retries a 5xx response until the server recovers", func() {
+			var mu sync.Mutex
+			attempts := 0
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					w.WriteHeader(http.StatusOK)
+					_, _ = w.Write([]byte(`<list><file><uri>/some-release-1.2.3-ubuntu-xenial-190.0.0.tgz</uri></file></list>`))
+					return
+				}
+
+				mu.Lock()
+				attempts++
+				attempt := attempts
+				mu.Unlock()
+
+				if attempt == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte("abc"))
+			}))
+
+			source := fetcher.NewArtifactoryReleaseSource(cargo.ReleaseSourceConfig{Type: "artifactory", Bucket: server.URL}, regex)
+
+			release := cargo.CompiledRelease{Name: "some-release", Version: "1.2.3", StemcellOS: "ubuntu-xenial", StemcellVersion: "190.0.0"}
+			_, err := source.List(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeLogger := log.New(GinkgoWriter, "", 0)
+			releasesService := baking.NewReleasesService(fakeLogger, builder.NewReleaseManifestReader())
+			directory := fetcher.NewLocalReleaseDirectory(fakeLogger, releasesService)
+
+			assetsLock := cargo.AssetsLock{
+				Releases: []cargo.Release{
+					{Name: "some-release", Version: "1.2.3", SHA1: "a9993e364706816aba3e25717850c26c9cd0d89d"}, // sha1 for "abc"
+				},
+				Stemcell: cargo.Stemcell{OS: "ubuntu-xenial", Version: "190.0.0"},
+			}
+
+			downloader := fetcher.NewDownloader(directory, fetcher.DownloaderConfig{Parallel: 1, MaxAttempts: 3, RatePerSecond: 1000, Burst: 10})
+			results := downloader.Download(context.Background(), releasesDir, map[cargo.CompiledRelease]fetcher.ReleaseSource{release: source}, assetsLock)
+
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Err).NotTo(HaveOccurred())
+			Expect(results[0].Attempts).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,136 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+// AzureBlobReleaseSource lists and downloads compiled releases stored as
+// blobs in an Azure Blob Storage container.
+type AzureBlobReleaseSource struct {
+	config cargo.ReleaseSourceConfig
+	regex  *CompiledReleasesRegexp
+
+	mu   sync.Mutex
+	keys map[cargo.CompiledRelease]string
+}
+
+// NewAzureBlobReleaseSource returns an AzureBlobReleaseSource for the
+// given Kilnfile sources: entry.
+func NewAzureBlobReleaseSource(config cargo.ReleaseSourceConfig, regex *CompiledReleasesRegexp) *AzureBlobReleaseSource {
+	return &AzureBlobReleaseSource{config: config, regex: regex, keys: map[cargo.CompiledRelease]string{}}
+}
+
+func (a *AzureBlobReleaseSource) Name() string { return "azure:" + a.config.Container }
+func (a *AzureBlobReleaseSource) Host() string { return a.config.Container }
+
+func (a *AzureBlobReleaseSource) containerURL() (azblob.ContainerURL, error) {
+	credential, err := azblob.NewSharedKeyCredential(a.config.Username, a.config.Password)
+	if err != nil {
+		return azblob.ContainerURL{}, fmt.Errorf("invalid azure storage credentials: %w", err)
+	}
+
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{})
+	endpoint, err := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", a.config.Username, a.config.Container))
+	if err != nil {
+		return azblob.ContainerURL{}, err
+	}
+
+	return azblob.NewContainerURL(*endpoint, pipeline), nil
+}
+
+// List enumerates every blob in the container whose name matches the
+// source's path_template.
+func (a *AzureBlobReleaseSource) List(ctx context.Context) ([]cargo.CompiledRelease, error) {
+	container, err := a.containerURL()
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []cargo.CompiledRelease
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		listBlob, err := container.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{})
+		if err != nil {
+			return nil, classifyAzureError(fmt.Errorf("failed to list blobs in container %q: %w", a.config.Container, err))
+		}
+
+		for _, blob := range listBlob.Segment.BlobItems {
+			release, err := a.regex.Convert(blob.Name)
+			if err != nil {
+				continue
+			}
+			releases = append(releases, release)
+
+			a.mu.Lock()
+			a.keys[release] = blob.Name
+			a.mu.Unlock()
+		}
+
+		marker = listBlob.NextMarker
+	}
+
+	return releases, nil
+}
+
+// Download fetches release from the container into releasesDir. release
+// must have come from a prior call to List.
+func (a *AzureBlobReleaseSource) Download(ctx context.Context, releasesDir string, release cargo.CompiledRelease) (string, int64, error) {
+	a.mu.Lock()
+	blobName, ok := a.keys[release]
+	a.mu.Unlock()
+	if !ok {
+		return "", 0, fmt.Errorf("release %s %s was not found by a prior List call", release.Name, release.Version)
+	}
+
+	container, err := a.containerURL()
+	if err != nil {
+		return "", 0, err
+	}
+
+	response, err := container.NewBlobURL(blobName).Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		return "", 0, classifyAzureError(fmt.Errorf("failed to download %s from container %q: %w", blobName, a.config.Container, err))
+	}
+
+	body := response.Body(azblob.RetryReaderOptions{})
+	defer body.Close()
+
+	path := filepath.Join(releasesDir, filepath.Base(blobName))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	bytesWritten, err := io.Copy(file, body)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return path, bytesWritten, nil
+}
+
+// classifyAzureError marks err as retryable if it is a timeout or a
+// StorageError whose underlying HTTP response is a 5xx, i.e. the kind of
+// error worth backing off and trying again rather than failing the
+// whole download.
+func classifyAzureError(err error) error {
+	var storageErr azblob.StorageError
+	if errors.As(err, &storageErr) {
+		if response := storageErr.Response(); response != nil && response.StatusCode >= http.StatusInternalServerError {
+			return MarkRetryable(err)
+		}
+	}
+	return classifyTimeout(err)
+}
@@ -0,0 +1,71 @@
+package fetcher
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+var requiredCaptureGroups = []string{"release_name", "release_version", "stemcell_os", "stemcell_version"}
+
+// CompiledReleasesRegexp converts release source keys (S3 keys, GCS
+// object names, blob paths, ...) into a cargo.CompiledRelease using a
+// regular expression with named capture groups.
+type CompiledReleasesRegexp struct {
+	re *regexp.Regexp
+}
+
+// NewCompiledReleasesRegexp compiles exp and verifies it declares all of
+// the named capture groups required to identify a compiled release.
+func NewCompiledReleasesRegexp(exp string) (*CompiledReleasesRegexp, error) {
+	re, err := regexp.Compile(exp)
+	if err != nil {
+		return nil, err
+	}
+
+	names := re.SubexpNames()
+
+	for _, required := range requiredCaptureGroups {
+		if !containsString(names, required) {
+			return nil, errors.New("path_template regex must contain the following named capture groups: " + strings.Join(requiredCaptureGroups, ", "))
+		}
+	}
+
+	return &CompiledReleasesRegexp{re: re}, nil
+}
+
+// Convert matches key against the compiled regex and builds the
+// cargo.CompiledRelease it identifies.
+func (c *CompiledReleasesRegexp) Convert(key string) (cargo.CompiledRelease, error) {
+	matches := c.re.FindStringSubmatch(key)
+	if matches == nil {
+		return cargo.CompiledRelease{}, errors.New("release source key does not match regex")
+	}
+
+	var release cargo.CompiledRelease
+	for i, name := range c.re.SubexpNames() {
+		switch name {
+		case "release_name":
+			release.Name = matches[i]
+		case "release_version":
+			release.Version = matches[i]
+		case "stemcell_os":
+			release.StemcellOS = matches[i]
+		case "stemcell_version":
+			release.StemcellVersion = matches[i]
+		}
+	}
+
+	return release, nil
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
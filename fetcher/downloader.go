@@ -0,0 +1,254 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+// DownloadResult describes the outcome of fetching a single release,
+// regardless of whether it ultimately succeeded.
+type DownloadResult struct {
+	Release      cargo.CompiledRelease
+	Path         string
+	Source       string
+	BytesWritten int64
+	Attempts     int
+	Elapsed      time.Duration
+	Err          error
+}
+
+// DownloaderConfig controls the concurrency, pacing, and retry behavior
+// of a Downloader.
+type DownloaderConfig struct {
+	// Parallel is the number of releases downloaded concurrently.
+	Parallel int
+
+	// RatePerSecond and Burst configure a per-host token-bucket limiter
+	// shared by every worker downloading from that host.
+	RatePerSecond float64
+	Burst         int
+
+	// MaxAttempts is the number of times a single release download is
+	// retried on a transient (5xx or timeout) failure before giving up.
+	MaxAttempts int
+}
+
+func (c DownloaderConfig) withDefaults() DownloaderConfig {
+	if c.Parallel <= 0 {
+		c.Parallel = 4
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.RatePerSecond <= 0 {
+		c.RatePerSecond = 5
+	}
+	if c.Burst <= 0 {
+		c.Burst = c.Parallel
+	}
+	return c
+}
+
+// downloadJob is a single release that needs to be fetched from source.
+type downloadJob struct {
+	release cargo.CompiledRelease
+	source  ReleaseSource
+	host    string
+}
+
+// Downloader concurrently fetches releases missing from a
+// LocalReleaseDirectory against a set of ReleaseSources,
+// verifying each one's checksum as soon as it lands so bad files don't
+// linger for the length of the whole batch.
+type Downloader struct {
+	config    DownloaderConfig
+	directory LocalReleaseDirectory
+
+	limitersMu sync.Mutex
+	limiters   map[string]*rate.Limiter
+}
+
+// NewDownloader returns a Downloader that verifies completed downloads
+// against directory.
+func NewDownloader(directory LocalReleaseDirectory, config DownloaderConfig) *Downloader {
+	return &Downloader{
+		config:    config.withDefaults(),
+		directory: directory,
+		limiters:  map[string]*rate.Limiter{},
+	}
+}
+
+// limiterFor returns the shared token-bucket limiter for host, creating
+// it on first use. Workers call this concurrently, so access to the
+// underlying map is guarded by a mutex.
+func (d *Downloader) limiterFor(host string) *rate.Limiter {
+	d.limitersMu.Lock()
+	defer d.limitersMu.Unlock()
+
+	limiter, ok := d.limiters[host]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(d.config.RatePerSecond), d.config.Burst)
+		d.limiters[host] = limiter
+	}
+	return limiter
+}
+
+// Download fetches every release in missing from source, writing the
+// results into releasesDir. It returns a DownloadResult per release, in
+// no particular order. If the context is cancelled, or any single
+// release exhausts its retries, the remaining in-flight downloads are
+// cancelled and no further downloads are started.
+func (d *Downloader) Download(ctx context.Context, releasesDir string, missing map[cargo.CompiledRelease]ReleaseSource, assetsLock cargo.AssetsLock) []DownloadResult {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan downloadJob, len(missing))
+	for release, source := range missing {
+		jobs <- downloadJob{release: release, source: source, host: source.Host()}
+	}
+	close(jobs)
+
+	results := make(chan DownloadResult, len(missing))
+
+	workers := d.config.Parallel
+	if workers > len(missing) {
+		workers = len(missing)
+	}
+	if workers == 0 {
+		return nil
+	}
+
+	for i := 0; i < workers; i++ {
+		go d.worker(ctx, cancel, releasesDir, assetsLock, jobs, results)
+	}
+
+	var all []DownloadResult
+	for range missing {
+		result := <-results
+		all = append(all, result)
+	}
+
+	return all
+}
+
+func (d *Downloader) worker(ctx context.Context, cancel context.CancelFunc, releasesDir string, assetsLock cargo.AssetsLock, jobs <-chan downloadJob, results chan<- DownloadResult) {
+	for job := range jobs {
+		select {
+		case <-ctx.Done():
+			results <- DownloadResult{Release: job.release, Err: ctx.Err()}
+			continue
+		default:
+		}
+
+		result := d.downloadOne(ctx, releasesDir, assetsLock, job)
+		if result.Err != nil {
+			cancel()
+		}
+
+		results <- result
+	}
+}
+
+func (d *Downloader) downloadOne(ctx context.Context, releasesDir string, assetsLock cargo.AssetsLock, job downloadJob) DownloadResult {
+	start := time.Now()
+	limiter := d.limiterFor(job.host)
+
+	var lastErr error
+	for attempt := 1; attempt <= d.config.MaxAttempts; attempt++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return DownloadResult{Release: job.release, Source: job.source.Name(), Attempts: attempt, Elapsed: time.Since(start), Err: err}
+		}
+
+		path, bytesWritten, err := job.source.Download(ctx, releasesDir, job.release)
+		if err == nil {
+			verifyErr := d.directory.VerifyChecksums(releasesDir, map[cargo.CompiledRelease]string{job.release: path}, assetsLock)
+			return DownloadResult{
+				Release:      job.release,
+				Path:         path,
+				Source:       job.source.Name(),
+				BytesWritten: bytesWritten,
+				Attempts:     attempt,
+				Elapsed:      time.Since(start),
+				Err:          verifyErr,
+			}
+		}
+
+		lastErr = err
+		if !isRetryable(err) || attempt == d.config.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			lastErr = ctx.Err()
+			attempt = d.config.MaxAttempts
+		case <-time.After(backoff(attempt)):
+		}
+	}
+
+	return DownloadResult{
+		Release:  job.release,
+		Source:   job.source.Name(),
+		Attempts: d.config.MaxAttempts,
+		Elapsed:  time.Since(start),
+		Err:      fmt.Errorf("failed to download %s from %s: %w", job.release.Name, job.source.Name(), lastErr),
+	}
+}
+
+// backoff returns an exponential backoff duration for the given attempt
+// number (1-indexed), with up to 20% jitter to avoid synchronized
+// retries across workers.
+func backoff(attempt int) time.Duration {
+	base := time.Second * time.Duration(1<<uint(attempt-1))
+	jitter := time.Duration(rand.Int63n(int64(base) / 5))
+	return base + jitter
+}
+
+// retryableError is implemented by ReleaseSource errors that represent a
+// transient failure (5xx responses, timeouts) worth retrying.
+type retryableError interface {
+	Retryable() bool
+}
+
+// transientError marks err as a retryableError. ReleaseSource
+// implementations should wrap 5xx responses, timeouts, and connection
+// resets with MarkRetryable so the Downloader's backoff loop kicks in.
+type transientError struct {
+	err error
+}
+
+// MarkRetryable wraps err so that isRetryable reports it as worth
+// retrying. A nil err returns nil.
+func MarkRetryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return transientError{err: err}
+}
+
+func (t transientError) Error() string   { return t.err.Error() }
+func (t transientError) Unwrap() error   { return t.err }
+func (t transientError) Retryable() bool { return true }
+
+func isRetryable(err error) bool {
+	var re retryableError
+	return errors.As(err, &re) && re.Retryable()
+}
+
+// classifyTimeout wraps err with MarkRetryable if it represents a
+// context timeout, so ReleaseSource implementations don't each need to
+// special-case context.DeadlineExceeded themselves.
+func classifyTimeout(err error) error {
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return MarkRetryable(err)
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+package fetcher_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/builder"
+	"github.com/pivotal-cf/kiln/fetcher"
+	"github.com/pivotal-cf/kiln/internal/baking"
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+// flakySource fails its first failUntil download attempts (retryable if
+// configured), then writes "abc" to releasesDir and succeeds.
+type flakySource struct {
+	mu        sync.Mutex
+	attempts  int
+	failUntil int
+	retryable bool
+}
+
+func (f *flakySource) Name() string { return "flaky" }
+func (f *flakySource) Host() string { return "flaky-host" }
+
+func (f *flakySource) List(context.Context) ([]cargo.CompiledRelease, error) {
+	return nil, nil
+}
+
+func (f *flakySource) Download(ctx context.Context, releasesDir string, release cargo.CompiledRelease) (string, int64, error) {
+	f.mu.Lock()
+	f.attempts++
+	attempt := f.attempts
+	f.mu.Unlock()
+
+	if attempt <= f.failUntil {
+		err := fmt.Errorf("temporary failure on attempt %d", attempt)
+		if f.retryable {
+			return "", 0, fetcher.MarkRetryable(err)
+		}
+		return "", 0, err
+	}
+
+	path := filepath.Join(releasesDir, release.Name+".tgz")
+	if err := ioutil.WriteFile(path, []byte("abc"), 0644); err != nil {
+		return "", 0, err
+	}
+	return path, 3, nil
+}
+
+var _ = Describe("Downloader", func() {
+	var (
+		releasesDir string
+		directory   fetcher.LocalReleaseDirectory
+		assetsLock  cargo.AssetsLock
+		release     cargo.CompiledRelease
+	)
+
+	BeforeEach(func() {
+		var err error
+		releasesDir, err = ioutil.TempDir("", "releases")
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeLogger := log.New(GinkgoWriter, "", 0)
+		releasesService := baking.NewReleasesService(fakeLogger, builder.NewReleaseManifestReader())
+		directory = fetcher.NewLocalReleaseDirectory(fakeLogger, releasesService)
+
+		release = cargo.CompiledRelease{Name: "some-release", Version: "1.2.3", StemcellOS: "ubuntu-xenial", StemcellVersion: "190.0.0"}
+		assetsLock = cargo.AssetsLock{
+			Releases: []cargo.Release{
+				{Name: "some-release", Version: "1.2.3", SHA1: "a9993e364706816aba3e25717850c26c9cd0d89d"}, // sha1 for "abc"
+			},
+			Stemcell: cargo.Stemcell{OS: "ubuntu-xenial", Version: "190.0.0"},
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(releasesDir)
+	})
+
+	It("retries a release whose download fails with a retryable error", func() {
+		source := &flakySource{failUntil: 1, retryable: true}
+		downloader := fetcher.NewDownloader(directory, fetcher.DownloaderConfig{Parallel: 1, MaxAttempts: 3, RatePerSecond: 1000, Burst: 10})
+
+		results := downloader.Download(context.Background(), releasesDir, map[cargo.CompiledRelease]fetcher.ReleaseSource{release: source}, assetsLock)
+
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Err).NotTo(HaveOccurred())
+		Expect(results[0].Attempts).To(Equal(2))
+	})
+
+	It("does not retry a release whose download fails with a non-retryable error", func() {
+		source := &flakySource{failUntil: 1, retryable: false}
+		downloader := fetcher.NewDownloader(directory, fetcher.DownloaderConfig{Parallel: 1, MaxAttempts: 3, RatePerSecond: 1000, Burst: 10})
+
+		results := downloader.Download(context.Background(), releasesDir, map[cargo.CompiledRelease]fetcher.ReleaseSource{release: source}, assetsLock)
+
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Err).To(HaveOccurred())
+	})
+
+	It("cancels sibling downloads and still returns a result for every release once one exhausts its retries", func() {
+		failingRelease := cargo.CompiledRelease{Name: "failing-release", Version: "1.2.3", StemcellOS: "ubuntu-xenial", StemcellVersion: "190.0.0"}
+		failingSource := &flakySource{failUntil: 1000, retryable: false}
+		goodSource := &flakySource{failUntil: 0}
+
+		downloader := fetcher.NewDownloader(directory, fetcher.DownloaderConfig{Parallel: 2, MaxAttempts: 1, RatePerSecond: 1000, Burst: 10})
+
+		missing := map[cargo.CompiledRelease]fetcher.ReleaseSource{
+			release:        goodSource,
+			failingRelease: failingSource,
+		}
+
+		results := downloader.Download(context.Background(), releasesDir, missing, assetsLock)
+
+		Expect(results).To(HaveLen(2))
+
+		var sawFailure bool
+		for _, result := range results {
+			if result.Release == failingRelease {
+				Expect(result.Err).To(HaveOccurred())
+				sawFailure = true
+			}
+		}
+		Expect(sawFailure).To(BeTrue())
+	})
+})
+
+var _ = Describe("MarkRetryable", func() {
+	It("returns nil for a nil error", func() {
+		Expect(fetcher.MarkRetryable(nil)).To(BeNil())
+	})
+
+	It("wraps the original error so errors.Is / Unwrap still work", func() {
+		original := errors.New("boom")
+		wrapped := fetcher.MarkRetryable(original)
+
+		Expect(errors.Is(wrapped, original)).To(BeTrue())
+	})
+})
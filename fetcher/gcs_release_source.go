@@ -0,0 +1,119 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+// GCSReleaseSource lists and downloads compiled releases stored as
+// objects in a Google Cloud Storage bucket.
+type GCSReleaseSource struct {
+	config cargo.ReleaseSourceConfig
+	regex  *CompiledReleasesRegexp
+
+	mu   sync.Mutex
+	keys map[cargo.CompiledRelease]string
+}
+
+// NewGCSReleaseSource returns a GCSReleaseSource for the given Kilnfile
+// sources: entry.
+func NewGCSReleaseSource(config cargo.ReleaseSourceConfig, regex *CompiledReleasesRegexp) *GCSReleaseSource {
+	return &GCSReleaseSource{config: config, regex: regex, keys: map[cargo.CompiledRelease]string{}}
+}
+
+func (g *GCSReleaseSource) Name() string { return "gcs:" + g.config.Bucket }
+func (g *GCSReleaseSource) Host() string { return g.config.Bucket }
+
+// List enumerates every object in the bucket whose name matches the
+// source's path_template.
+func (g *GCSReleaseSource) List(ctx context.Context) ([]cargo.CompiledRelease, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	defer client.Close()
+
+	var releases []cargo.CompiledRelease
+	objects := client.Bucket(g.config.Bucket).Objects(ctx, nil)
+	for {
+		object, err := objects.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, classifyGCSError(fmt.Errorf("failed to list objects in bucket %q: %w", g.config.Bucket, err))
+		}
+
+		release, err := g.regex.Convert(object.Name)
+		if err != nil {
+			continue
+		}
+		releases = append(releases, release)
+
+		g.mu.Lock()
+		g.keys[release] = object.Name
+		g.mu.Unlock()
+	}
+
+	return releases, nil
+}
+
+// Download fetches release from the bucket into releasesDir. release
+// must have come from a prior call to List.
+func (g *GCSReleaseSource) Download(ctx context.Context, releasesDir string, release cargo.CompiledRelease) (string, int64, error) {
+	g.mu.Lock()
+	objectName, ok := g.keys[release]
+	g.mu.Unlock()
+	if !ok {
+		return "", 0, fmt.Errorf("release %s %s was not found by a prior List call", release.Name, release.Version)
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	defer client.Close()
+
+	reader, err := client.Bucket(g.config.Bucket).Object(objectName).NewReader(ctx)
+	if err != nil {
+		return "", 0, classifyGCSError(fmt.Errorf("failed to download %s from bucket %q: %w", objectName, g.config.Bucket, err))
+	}
+	defer reader.Close()
+
+	path := filepath.Join(releasesDir, filepath.Base(objectName))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	bytesWritten, err := io.Copy(file, reader)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return path, bytesWritten, nil
+}
+
+// classifyGCSError marks err as retryable if it is a timeout or a GCS
+// API error with a 5xx status, i.e. the kind of error worth backing off
+// and trying again rather than failing the whole download.
+func classifyGCSError(err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code >= http.StatusInternalServerError {
+		return MarkRetryable(err)
+	}
+	return classifyTimeout(err)
+}
@@ -0,0 +1,370 @@
+package fetcher
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+// ReleasesService identifies the compiled release a release tarball
+// contains.
+type ReleasesService interface {
+	CompiledRelease(path string) (cargo.CompiledRelease, error)
+}
+
+// Hasher produces the checksum implementation used to verify a
+// downloaded release against the digest recorded in a Kilnfile.lock.
+type Hasher interface {
+	// Name is the algorithm prefix used in a lock file checksum, e.g. "sha256".
+	Name() string
+	New() hash.Hash
+}
+
+type sha1Hasher struct{}
+
+func (sha1Hasher) Name() string   { return "sha1" }
+func (sha1Hasher) New() hash.Hash { return sha1.New() }
+
+type sha256Hasher struct{}
+
+func (sha256Hasher) Name() string   { return "sha256" }
+func (sha256Hasher) New() hash.Hash { return sha256.New() }
+
+type sha512Hasher struct{}
+
+func (sha512Hasher) Name() string   { return "sha512" }
+func (sha512Hasher) New() hash.Hash { return sha512.New() }
+
+var supportedHashers = []Hasher{sha1Hasher{}, sha256Hasher{}, sha512Hasher{}}
+
+// hasherForChecksum parses a lock file checksum of the form
+// "algorithm:hexdigest" and returns the Hasher it names along with the
+// bare hex digest. Checksums with no "algorithm:" prefix are assumed to
+// be sha1, matching kiln's historical behavior.
+func hasherForChecksum(checksum string) (Hasher, string) {
+	algorithm, digest, found := strings.Cut(checksum, ":")
+	if !found {
+		return sha1Hasher{}, checksum
+	}
+
+	for _, hasher := range supportedHashers {
+		if hasher.Name() == algorithm {
+			return hasher, digest
+		}
+	}
+
+	return sha1Hasher{}, checksum
+}
+
+// LocalReleaseDirectory locates, verifies, and prunes compiled release
+// tarballs in a tile's releases directory, optionally backed by a
+// shared, content-addressable cache on disk.
+type LocalReleaseDirectory struct {
+	logger          *log.Logger
+	releasesService ReleasesService
+	cacheRoot       string
+}
+
+// NewLocalReleaseDirectory returns a LocalReleaseDirectory with no
+// backing cache: every fetch re-downloads releases into releasesDir.
+func NewLocalReleaseDirectory(logger *log.Logger, releasesService ReleasesService) LocalReleaseDirectory {
+	return LocalReleaseDirectory{
+		logger:          logger,
+		releasesService: releasesService,
+	}
+}
+
+// NewLocalReleaseDirectoryWithCache returns a LocalReleaseDirectory that
+// keeps a content-addressable copy of every release it sees under root,
+// so that releases already downloaded for one tile can be reused by
+// another without hitting the release source again.
+func NewLocalReleaseDirectoryWithCache(logger *log.Logger, releasesService ReleasesService, root string) LocalReleaseDirectory {
+	return LocalReleaseDirectory{
+		logger:          logger,
+		releasesService: releasesService,
+		cacheRoot:       root,
+	}
+}
+
+// DefaultCacheRoot returns the OS-appropriate root for kiln's release
+// cache: $XDG_CACHE_HOME/kiln/releases if set, otherwise
+// ~/.cache/kiln/releases.
+func DefaultCacheRoot() (string, error) {
+	if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "kiln", "releases"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine a cache root: %w", err)
+	}
+
+	return filepath.Join(home, ".cache", "kiln", "releases"), nil
+}
+
+// cacheEntry is the sidecar metadata kiln writes next to a cached
+// release blob so the blob can be reassociated with a cargo.CompiledRelease
+// without re-reading the tarball.
+type cacheEntry struct {
+	Release  cargo.CompiledRelease `json:"release"`
+	Filename string                `json:"filename"`
+}
+
+func (l LocalReleaseDirectory) cachePaths(algorithm, digest string) (blobPath, metaPath string) {
+	dir := filepath.Join(l.cacheRoot, algorithm)
+	return filepath.Join(dir, digest), filepath.Join(dir, digest+".json")
+}
+
+// PutInCache stores a copy of the release tarball at path under the
+// cache root, keyed by its digest under the given algorithm, so that it
+// can be reused across tiles. It is a no-op when no cache root is
+// configured.
+func (l LocalReleaseDirectory) PutInCache(hasher Hasher, digest string, release cargo.CompiledRelease, path string) error {
+	if l.cacheRoot == "" {
+		return nil
+	}
+
+	blobPath, metaPath := l.cachePaths(hasher.Name(), digest)
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(blobPath); err == nil {
+		return nil // already cached
+	}
+
+	if err := linkOrCopy(path, blobPath); err != nil {
+		return fmt.Errorf("failed to cache release %s: %w", release.Name, err)
+	}
+
+	entry := cacheEntry{Release: release, Filename: filepath.Base(path)}
+	contents, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(metaPath, contents, 0644)
+}
+
+// GetLocalReleases returns every compiled release found in releasesDir,
+// populating it first with any releases already present in the shared
+// cache so that repeated fetches across tiles can reuse bytes on disk
+// instead of downloading them again.
+func (l LocalReleaseDirectory) GetLocalReleases(releasesDir string) (map[cargo.CompiledRelease]string, error) {
+	if l.cacheRoot != "" {
+		if err := l.populateFromCache(releasesDir); err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := ioutil.ReadDir(releasesDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading releases directory %q: %w", releasesDir, err)
+	}
+
+	releases := map[cargo.CompiledRelease]string{}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(releasesDir, file.Name())
+
+		compiledRelease, err := l.releasesService.CompiledRelease(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not identify release in %q: %w", path, err)
+		}
+
+		releases[compiledRelease] = path
+	}
+
+	return releases, nil
+}
+
+// populateFromCache hard-links (falling back to a copy across devices)
+// every cached release not already present in releasesDir.
+func (l LocalReleaseDirectory) populateFromCache(releasesDir string) error {
+	algorithmDirs, err := ioutil.ReadDir(l.cacheRoot)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, algorithmDir := range algorithmDirs {
+		if !algorithmDir.IsDir() {
+			continue
+		}
+
+		metas, err := filepath.Glob(filepath.Join(l.cacheRoot, algorithmDir.Name(), "*.json"))
+		if err != nil {
+			return err
+		}
+
+		for _, metaPath := range metas {
+			contents, err := ioutil.ReadFile(metaPath)
+			if err != nil {
+				return err
+			}
+
+			var entry cacheEntry
+			if err := json.Unmarshal(contents, &entry); err != nil {
+				return err
+			}
+
+			destination := filepath.Join(releasesDir, entry.Filename)
+			if _, err := os.Stat(destination); err == nil {
+				continue // already present locally
+			}
+
+			blobPath := strings.TrimSuffix(metaPath, ".json")
+			if err := linkOrCopy(blobPath, destination); err != nil {
+				return fmt.Errorf("failed to populate %s from cache: %w", entry.Filename, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// linkOrCopy hard-links dst to src, falling back to a byte copy when the
+// two paths do not share a device (e.g. the cache root is on another
+// filesystem).
+func linkOrCopy(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// DeleteExtraReleases removes every release in extraReleases from disk,
+// prompting for confirmation unless noConfirm is set.
+func (l LocalReleaseDirectory) DeleteExtraReleases(releasesDir string, extraReleases map[cargo.CompiledRelease]string, noConfirm bool) error {
+	if len(extraReleases) == 0 {
+		return nil
+	}
+
+	if !noConfirm {
+		l.logger.Println("kiln needs to delete the following releases:")
+		for release := range extraReleases {
+			l.logger.Printf("  %s %s\n", release.Name, release.Version)
+		}
+		l.logger.Println("are you sure you want to delete these files? [yN]")
+
+		reader := bufio.NewReader(os.Stdin)
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(strings.ToLower(answer)) != "y" {
+			return nil
+		}
+	}
+
+	for release, path := range extraReleases {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to delete release %s", release.Name)
+		}
+	}
+
+	return nil
+}
+
+// VerifyChecksums streams every downloaded release through the hasher
+// named in its Kilnfile.lock checksum (sha1 when unspecified, for
+// backward compatibility) and deletes any release whose digest does not
+// match. Releases that verify are also stored in the shared cache, if
+// one is configured, so later fetches across tiles can reuse them.
+func (l LocalReleaseDirectory) VerifyChecksums(releasesDir string, downloadedReleases map[cargo.CompiledRelease]string, assetsLock cargo.AssetsLock) error {
+	var mismatchedReleases []string
+
+	for release, path := range downloadedReleases {
+		lockedRelease, err := findRelease(assetsLock, release)
+		if err != nil {
+			return err
+		}
+
+		hasher, expectedDigest := hasherForChecksum(lockedRelease.SHA1)
+
+		actualDigest, err := streamingDigest(hasher, path)
+		if err != nil {
+			return err
+		}
+
+		if actualDigest != expectedDigest {
+			mismatchedReleases = append(mismatchedReleases, release.Name)
+
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to delete release %s: %w", release.Name, err)
+			}
+			continue
+		}
+
+		if err := l.PutInCache(hasher, actualDigest, release, path); err != nil {
+			l.logger.Printf("warning: failed to cache release %s: %v", release.Name, err)
+		}
+	}
+
+	if len(mismatchedReleases) > 0 {
+		return fmt.Errorf("These downloaded releases do not match the checksum: %s", strings.Join(mismatchedReleases, ", "))
+	}
+
+	return nil
+}
+
+func findRelease(assetsLock cargo.AssetsLock, release cargo.CompiledRelease) (cargo.Release, error) {
+	for _, lockedRelease := range assetsLock.Releases {
+		if lockedRelease.Name == release.Name && lockedRelease.Version == release.Version {
+			return lockedRelease, nil
+		}
+	}
+
+	return cargo.Release{}, fmt.Errorf("no release named %q found in Kilnfile.lock", release.Name)
+}
+
+func streamingDigest(hasher Hasher, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := hasher.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,240 @@
+package fetcher
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+// PruneSpec controls which cached releases fetcher.Prune is allowed to
+// reclaim.
+type PruneSpec struct {
+	// KeepReferencedBy is a set of tile directories (or glob patterns
+	// matching them); any release referenced by a Kilnfile.lock found
+	// under one of these is kept regardless of age or size pressure.
+	KeepReferencedBy []string
+
+	// MaxAge, if non-zero, deletes unreferenced releases whose cache
+	// entry is older than this.
+	MaxAge time.Duration
+
+	// MaxSize, if non-zero, deletes the least-recently-used unreferenced
+	// releases until the cache is at or under this many bytes.
+	MaxSize int64
+
+	// DryRun reports what would be deleted without deleting anything.
+	DryRun bool
+
+	// NoConfirm skips the interactive confirmation prompt.
+	NoConfirm bool
+}
+
+// PruneResult summarizes the outcome of a Prune call.
+type PruneResult struct {
+	DeletedReleases []cargo.CompiledRelease
+	ReclaimedBytes  int64
+}
+
+type cacheItem struct {
+	entry    cacheEntry
+	blobPath string
+	metaPath string
+	size     int64
+	modTime  time.Time
+}
+
+// Prune deletes cached releases under root that are not referenced by
+// any Kilnfile.lock in keep.KeepReferencedBy, subject to keep.MaxAge and
+// keep.MaxSize.
+func (l LocalReleaseDirectory) Prune(root string, keep PruneSpec) (PruneResult, error) {
+	items, err := cacheItems(root)
+	if err != nil {
+		return PruneResult{}, err
+	}
+
+	referenced, err := referencedDigests(keep.KeepReferencedBy)
+	if err != nil {
+		return PruneResult{}, err
+	}
+
+	var candidates []cacheItem
+	for _, item := range items {
+		digest := filepath.Base(item.blobPath)
+		if referenced[digest] {
+			continue
+		}
+		candidates = append(candidates, item)
+	}
+
+	toDelete := map[string]cacheItem{}
+
+	if keep.MaxAge > 0 {
+		cutoff := time.Now().Add(-keep.MaxAge)
+		for _, item := range candidates {
+			if item.modTime.Before(cutoff) {
+				toDelete[item.blobPath] = item
+			}
+		}
+	}
+
+	if keep.MaxSize > 0 {
+		var total int64
+		for _, item := range items {
+			total += item.size
+		}
+		for _, item := range toDelete {
+			total -= item.size
+		}
+
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].modTime.Before(candidates[j].modTime)
+		})
+
+		for _, item := range candidates {
+			if total <= keep.MaxSize {
+				break
+			}
+			if _, alreadyMarked := toDelete[item.blobPath]; alreadyMarked {
+				continue
+			}
+			toDelete[item.blobPath] = item
+			total -= item.size
+		}
+	}
+
+	if len(toDelete) == 0 {
+		return PruneResult{}, nil
+	}
+
+	if !keep.DryRun && !keep.NoConfirm {
+		l.logger.Println("kiln would like to prune the following cached releases:")
+		for _, item := range toDelete {
+			l.logger.Printf("  %s %s (%d bytes)\n", item.entry.Release.Name, item.entry.Release.Version, item.size)
+		}
+		l.logger.Println("are you sure you want to delete these files? [yN]")
+
+		reader := bufio.NewReader(os.Stdin)
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			return PruneResult{}, err
+		}
+		if strings.TrimSpace(strings.ToLower(answer)) != "y" {
+			return PruneResult{}, nil
+		}
+	}
+
+	result := PruneResult{}
+	for _, item := range toDelete {
+		result.DeletedReleases = append(result.DeletedReleases, item.entry.Release)
+		result.ReclaimedBytes += item.size
+
+		if keep.DryRun {
+			continue
+		}
+
+		if err := os.Remove(item.blobPath); err != nil && !os.IsNotExist(err) {
+			return PruneResult{}, fmt.Errorf("failed to prune release %s: %w", item.entry.Release.Name, err)
+		}
+		if err := os.Remove(item.metaPath); err != nil && !os.IsNotExist(err) {
+			return PruneResult{}, fmt.Errorf("failed to prune release %s: %w", item.entry.Release.Name, err)
+		}
+	}
+
+	return result, nil
+}
+
+func cacheItems(root string) ([]cacheItem, error) {
+	algorithmDirs, err := ioutil.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var items []cacheItem
+	for _, algorithmDir := range algorithmDirs {
+		if !algorithmDir.IsDir() {
+			continue
+		}
+
+		metaPaths, err := filepath.Glob(filepath.Join(root, algorithmDir.Name(), "*.json"))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, metaPath := range metaPaths {
+			contents, err := ioutil.ReadFile(metaPath)
+			if err != nil {
+				return nil, err
+			}
+
+			var entry cacheEntry
+			if err := json.Unmarshal(contents, &entry); err != nil {
+				return nil, err
+			}
+
+			blobPath := strings.TrimSuffix(metaPath, ".json")
+			info, err := os.Stat(blobPath)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			items = append(items, cacheItem{
+				entry:    entry,
+				blobPath: blobPath,
+				metaPath: metaPath,
+				size:     info.Size(),
+				modTime:  info.ModTime(),
+			})
+		}
+	}
+
+	return items, nil
+}
+
+// referencedDigests returns the set of release digests locked by any
+// Kilnfile.lock found under tileDirGlobs.
+func referencedDigests(tileDirGlobs []string) (map[string]bool, error) {
+	referenced := map[string]bool{}
+
+	for _, pattern := range tileDirGlobs {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dir := range matches {
+			lockPath := filepath.Join(dir, "Kilnfile.lock")
+			contents, err := ioutil.ReadFile(lockPath)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			var lock cargo.AssetsLock
+			if err := yaml.Unmarshal(contents, &lock); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", lockPath, err)
+			}
+
+			for _, release := range lock.Releases {
+				_, digest := hasherForChecksum(release.SHA1)
+				referenced[digest] = true
+			}
+		}
+	}
+
+	return referenced, nil
+}
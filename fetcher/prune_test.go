@@ -0,0 +1,119 @@
+package fetcher_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"hash"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/builder"
+	"github.com/pivotal-cf/kiln/fetcher"
+	"github.com/pivotal-cf/kiln/internal/baking"
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+type testHasher struct{}
+
+func (testHasher) Name() string   { return "sha1" }
+func (testHasher) New() hash.Hash { return sha1.New() }
+
+var _ = Describe("LocalReleaseDirectory Prune", func() {
+	var (
+		cacheRoot             string
+		sourceDir             string
+		localReleaseDirectory fetcher.LocalReleaseDirectory
+		fakeLogger            *log.Logger
+	)
+
+	BeforeEach(func() {
+		var err error
+		cacheRoot, err = ioutil.TempDir("", "release-cache")
+		Expect(err).NotTo(HaveOccurred())
+
+		sourceDir, err = ioutil.TempDir("", "release-source")
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeLogger = log.New(GinkgoWriter, "", 0)
+		releaseManifestReader := builder.NewReleaseManifestReader()
+		releasesService := baking.NewReleasesService(fakeLogger, releaseManifestReader)
+
+		localReleaseDirectory = fetcher.NewLocalReleaseDirectoryWithCache(fakeLogger, releasesService, cacheRoot)
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(cacheRoot)
+		_ = os.RemoveAll(sourceDir)
+	})
+
+	// seed writes content to the cache under release's identity and backdates
+	// the cache entry's mtime by age, returning the digest it was cached under.
+	seed := func(release cargo.CompiledRelease, content string, age time.Duration) string {
+		sourcePath := filepath.Join(sourceDir, release.Name+"-"+release.Version+".tgz")
+		Expect(ioutil.WriteFile(sourcePath, []byte(content), 0644)).To(Succeed())
+
+		sum := sha1.Sum([]byte(content))
+		digest := hex.EncodeToString(sum[:])
+
+		Expect(localReleaseDirectory.PutInCache(testHasher{}, digest, release, sourcePath)).To(Succeed())
+
+		mtime := time.Now().Add(-age)
+		Expect(filepath.Walk(cacheRoot, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			return os.Chtimes(path, mtime, mtime)
+		})).To(Succeed())
+
+		return digest
+	}
+
+	Describe("combining MaxAge and MaxSize", func() {
+		It("does not double-count bytes already reclaimed by MaxAge when enforcing MaxSize", func() {
+			old := cargo.CompiledRelease{Name: "old-release", Version: "1.0.0", StemcellOS: "ubuntu-xenial", StemcellVersion: "1"}
+			recent := cargo.CompiledRelease{Name: "recent-release", Version: "1.0.0", StemcellOS: "ubuntu-xenial", StemcellVersion: "1"}
+
+			seed(old, "0123456789", 40*24*time.Hour)
+			seed(recent, "9876543210", 24*time.Hour)
+
+			result, err := localReleaseDirectory.Prune(cacheRoot, fetcher.PruneSpec{
+				MaxAge:    30 * 24 * time.Hour,
+				MaxSize:   15,
+				NoConfirm: true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.DeletedReleases).To(ConsistOf(old))
+			Expect(result.ReclaimedBytes).To(Equal(int64(10)))
+		})
+	})
+
+	Describe("KeepReferencedBy", func() {
+		It("never prunes a release referenced by a Kilnfile.lock under a kept tile directory", func() {
+			referenced := cargo.CompiledRelease{Name: "referenced-release", Version: "1.0.0", StemcellOS: "ubuntu-xenial", StemcellVersion: "1"}
+			digest := seed(referenced, "abc", 60*24*time.Hour)
+
+			tileDir, err := ioutil.TempDir("", "tile")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tileDir)
+
+			lockContents := "releases:\n- name: referenced-release\n  version: 1.0.0\n  sha1: " + digest + "\n"
+			Expect(ioutil.WriteFile(filepath.Join(tileDir, "Kilnfile.lock"), []byte(lockContents), 0644)).To(Succeed())
+
+			result, err := localReleaseDirectory.Prune(cacheRoot, fetcher.PruneSpec{
+				KeepReferencedBy: []string{tileDir},
+				MaxAge:           24 * time.Hour,
+				NoConfirm:        true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.DeletedReleases).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,98 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+// ReleaseSource locates and downloads compiled releases from a single
+// backend (an S3 bucket, a GCS bucket, an Azure Blob container, an
+// Artifactory repository, ...).
+type ReleaseSource interface {
+	// Name identifies the source in logs and in the Kilnfile.lock's
+	// remote_source field.
+	Name() string
+
+	// Host identifies the backend this source talks to, for the
+	// purposes of per-host rate limiting. Two sources with the same Host
+	// share a token bucket.
+	Host() string
+
+	// List enumerates every compiled release this source currently
+	// holds.
+	List(ctx context.Context) ([]cargo.CompiledRelease, error)
+
+	// Download fetches release into releasesDir, returning the path it
+	// was written to and the number of bytes written.
+	Download(ctx context.Context, releasesDir string, release cargo.CompiledRelease) (path string, bytesWritten int64, err error)
+}
+
+// NewReleaseSource builds the ReleaseSource named by config.Type.
+func NewReleaseSource(config cargo.ReleaseSourceConfig) (ReleaseSource, error) {
+	regex, err := NewCompiledReleasesRegexp(config.PathTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path_template for source %q: %w", config.Type, err)
+	}
+
+	switch config.Type {
+	case "s3":
+		return NewS3ReleaseSource(config, regex), nil
+	case "gcs":
+		return NewGCSReleaseSource(config, regex), nil
+	case "azure":
+		return NewAzureBlobReleaseSource(config, regex), nil
+	case "artifactory":
+		return NewArtifactoryReleaseSource(config, regex), nil
+	default:
+		return nil, fmt.Errorf("unknown release source type %q", config.Type)
+	}
+}
+
+// NewReleaseSources builds a ReleaseSource for each entry in configs, in
+// the order given.
+func NewReleaseSources(configs []cargo.ReleaseSourceConfig) ([]ReleaseSource, error) {
+	sources := make([]ReleaseSource, 0, len(configs))
+	for _, config := range configs {
+		source, err := NewReleaseSource(config)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// FindReleases iterates sources in the given priority order and, for
+// every release in missing not yet found, records the first source that
+// lists it. Releases found by no source are omitted from the result.
+func FindReleases(ctx context.Context, sources []ReleaseSource, missing []cargo.CompiledRelease) (map[cargo.CompiledRelease]ReleaseSource, error) {
+	remaining := map[cargo.CompiledRelease]bool{}
+	for _, release := range missing {
+		remaining[release] = true
+	}
+
+	found := map[cargo.CompiledRelease]ReleaseSource{}
+
+	for _, source := range sources {
+		if len(remaining) == 0 {
+			break
+		}
+
+		available, err := source.List(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not list releases from %s: %w", source.Name(), err)
+		}
+
+		for _, release := range available {
+			if remaining[release] {
+				found[release] = source
+				delete(remaining, release)
+			}
+		}
+	}
+
+	return found, nil
+}
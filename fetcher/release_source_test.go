@@ -0,0 +1,91 @@
+package fetcher_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/fetcher"
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+var _ = Describe("NewReleaseSource", func() {
+	validConfig := func(sourceType string) cargo.ReleaseSourceConfig {
+		return cargo.ReleaseSourceConfig{
+			Type:         sourceType,
+			Bucket:       "some-bucket",
+			Container:    "some-container",
+			PathTemplate: `^(?P<release_name>[a-z-_]+)-(?P<release_version>[0-9\.]+)-(?P<stemcell_os>[a-z-_]+)-(?P<stemcell_version>[\d\.]+)\.tgz$`,
+		}
+	}
+
+	DescribeTable("builds the release source matching Type",
+		func(sourceType string, expectedName string) {
+			source, err := fetcher.NewReleaseSource(validConfig(sourceType))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(source.Name()).To(Equal(expectedName))
+		},
+		Entry("s3", "s3", "s3:some-bucket"),
+		Entry("gcs", "gcs", "gcs:some-bucket"),
+		Entry("azure", "azure", "azure:some-container"),
+		Entry("artifactory", "artifactory", "artifactory:some-bucket"),
+	)
+
+	It("returns an error for an unknown type", func() {
+		config := validConfig("smb")
+		_, err := fetcher.NewReleaseSource(config)
+		Expect(err).To(MatchError(ContainSubstring(`unknown release source type "smb"`)))
+	})
+
+	It("returns an error when path_template is missing required capture groups", func() {
+		config := validConfig("s3")
+		config.PathTemplate = `^(?P<release_name>[a-z-_]+)\.tgz$`
+
+		_, err := fetcher.NewReleaseSource(config)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid path_template"))
+	})
+})
+
+type fakeReleaseSource struct {
+	name     string
+	releases []cargo.CompiledRelease
+}
+
+func (f *fakeReleaseSource) Name() string { return f.name }
+func (f *fakeReleaseSource) Host() string { return f.name }
+
+func (f *fakeReleaseSource) List(context.Context) ([]cargo.CompiledRelease, error) {
+	return f.releases, nil
+}
+
+func (f *fakeReleaseSource) Download(context.Context, string, cargo.CompiledRelease) (string, int64, error) {
+	return "", 0, nil
+}
+
+var _ = Describe("FindReleases", func() {
+	It("records the first source, in priority order, that lists each release", func() {
+		wanted := cargo.CompiledRelease{Name: "uaa", Version: "1.2.3", StemcellOS: "ubuntu-xenial", StemcellVersion: "190.0.0"}
+		onlyInSecond := cargo.CompiledRelease{Name: "credhub", Version: "2.0.0", StemcellOS: "ubuntu-xenial", StemcellVersion: "190.0.0"}
+
+		first := &fakeReleaseSource{name: "first", releases: []cargo.CompiledRelease{wanted}}
+		second := &fakeReleaseSource{name: "second", releases: []cargo.CompiledRelease{wanted, onlyInSecond}}
+
+		found, err := fetcher.FindReleases(context.Background(), []fetcher.ReleaseSource{first, second}, []cargo.CompiledRelease{wanted, onlyInSecond})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(found).To(HaveLen(2))
+		Expect(found[wanted].Name()).To(Equal("first"))
+		Expect(found[onlyInSecond].Name()).To(Equal("second"))
+	})
+
+	It("omits releases found by no source", func() {
+		missing := cargo.CompiledRelease{Name: "nowhere", Version: "1.0.0"}
+		source := &fakeReleaseSource{name: "only"}
+
+		found, err := fetcher.FindReleases(context.Background(), []fetcher.ReleaseSource{source}, []cargo.CompiledRelease{missing})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeEmpty())
+	})
+})
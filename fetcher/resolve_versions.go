@@ -0,0 +1,147 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pivotal-cf/kiln/internal/cargo"
+	"github.com/pivotal-cf/kiln/internal/cargo/versions"
+)
+
+// ResolveVersions expands every release in kilnfile whose version is a
+// selector expression (rather than a pinned concrete version) into the
+// highest available version satisfying that selector, downloads it, and
+// returns a Kilnfile.lock with the resolved concrete versions and
+// checksums computed with hasher (sha1Hasher{} if nil, matching the
+// lock's historical default). Releases already pinned to a concrete
+// version are passed through unchanged and are not re-downloaded if
+// already present in releasesDir.
+func ResolveVersions(ctx context.Context, sources []ReleaseSource, directory LocalReleaseDirectory, releasesDir string, kilnfile cargo.Kilnfile, hasher Hasher) (cargo.AssetsLock, error) {
+	if hasher == nil {
+		hasher = sha1Hasher{}
+	}
+
+	available := map[ReleaseSource][]cargo.CompiledRelease{}
+	for _, source := range sources {
+		releases, err := source.List(ctx)
+		if err != nil {
+			return cargo.AssetsLock{}, fmt.Errorf("could not list releases from %s: %w", source.Name(), err)
+		}
+		available[source] = releases
+	}
+
+	stemcellSelector, err := versions.ParseSpec(kilnfile.Stemcell.Version)
+	if err != nil {
+		return cargo.AssetsLock{}, err
+	}
+
+	resolvedStemcellVersion, err := resolveStemcellVersion(stemcellSelector, available)
+	if err != nil {
+		return cargo.AssetsLock{}, err
+	}
+
+	stemcell := cargo.Stemcell{OS: kilnfile.Stemcell.OS, Version: resolvedStemcellVersion.String()}
+	lock := cargo.AssetsLock{Stemcell: stemcell}
+
+	for _, spec := range kilnfile.Releases {
+		selector, err := versions.ParseSpec(spec.Version)
+		if err != nil {
+			return cargo.AssetsLock{}, err
+		}
+
+		release, source, err := resolveHighest(selector, spec.Name, stemcell, available)
+		if err != nil {
+			return cargo.AssetsLock{}, err
+		}
+
+		path, _, err := source.Download(ctx, releasesDir, release)
+		if err != nil {
+			return cargo.AssetsLock{}, err
+		}
+
+		digest, err := streamingDigest(hasher, path)
+		if err != nil {
+			return cargo.AssetsLock{}, err
+		}
+
+		checksum := digest
+		if hasher.Name() != "sha1" {
+			checksum = hasher.Name() + ":" + digest
+		}
+
+		lock.Releases = append(lock.Releases, cargo.Release{
+			Name:    release.Name,
+			Version: release.Version,
+			SHA1:    checksum,
+			Source:  source.Name(),
+		})
+
+		if err := directory.PutInCache(hasher, digest, release, path); err != nil {
+			return cargo.AssetsLock{}, fmt.Errorf("failed to cache release %s: %w", release.Name, err)
+		}
+	}
+
+	return lock, nil
+}
+
+// resolveStemcellVersion finds the highest stemcell version, across
+// every source's listing of compiled releases, that satisfies selector.
+func resolveStemcellVersion(selector versions.Selector, available map[ReleaseSource][]cargo.CompiledRelease) (versions.Concrete, error) {
+	var (
+		best  versions.Concrete
+		found bool
+	)
+
+	for _, releases := range available {
+		for _, release := range releases {
+			concrete, err := versions.ParseConcrete(release.StemcellVersion)
+			if err != nil || !selector.Matches(concrete) {
+				continue
+			}
+
+			if !found || concrete.Compare(best) > 0 {
+				best, found = concrete, true
+			}
+		}
+	}
+
+	if !found {
+		return versions.Concrete{}, fmt.Errorf("no stemcell version satisfying %q was found among the available releases", selector.String())
+	}
+
+	return best, nil
+}
+
+// resolveHighest finds the highest version of name, compiled against
+// stemcell, across every source's listing that satisfies selector.
+func resolveHighest(selector versions.Selector, name string, stemcell cargo.Stemcell, available map[ReleaseSource][]cargo.CompiledRelease) (cargo.CompiledRelease, ReleaseSource, error) {
+	var (
+		best         cargo.CompiledRelease
+		bestSource   ReleaseSource
+		bestConcrete versions.Concrete
+		found        bool
+	)
+
+	for source, releases := range available {
+		for _, release := range releases {
+			if release.Name != name || release.StemcellOS != stemcell.OS || release.StemcellVersion != stemcell.Version {
+				continue
+			}
+
+			concrete, err := versions.ParseConcrete(release.Version)
+			if err != nil || !selector.Matches(concrete) {
+				continue
+			}
+
+			if !found || concrete.Compare(bestConcrete) > 0 {
+				best, bestSource, bestConcrete, found = release, source, concrete, true
+			}
+		}
+	}
+
+	if !found {
+		return cargo.CompiledRelease{}, nil, fmt.Errorf("no release named %q satisfying %q was found for stemcell %s/%s", name, selector.String(), stemcell.OS, stemcell.Version)
+	}
+
+	return best, bestSource, nil
+}
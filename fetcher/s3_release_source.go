@@ -0,0 +1,116 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+// S3ReleaseSource lists and downloads compiled releases stored as
+// objects in an S3 bucket.
+type S3ReleaseSource struct {
+	config cargo.ReleaseSourceConfig
+	regex  *CompiledReleasesRegexp
+
+	mu   sync.Mutex
+	keys map[cargo.CompiledRelease]string
+}
+
+// NewS3ReleaseSource returns an S3ReleaseSource for the given Kilnfile
+// sources: entry.
+func NewS3ReleaseSource(config cargo.ReleaseSourceConfig, regex *CompiledReleasesRegexp) *S3ReleaseSource {
+	return &S3ReleaseSource{config: config, regex: regex, keys: map[cargo.CompiledRelease]string{}}
+}
+
+func (s *S3ReleaseSource) Name() string { return "s3:" + s.config.Bucket }
+func (s *S3ReleaseSource) Host() string { return s.config.Bucket }
+
+func (s *S3ReleaseSource) client() *s3.S3 {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String(s.config.Region),
+		Credentials: credentials.NewStaticCredentials(s.config.AccessKeyId, s.config.SecretKey, ""),
+	}))
+	return s3.New(sess)
+}
+
+// List enumerates every object in the bucket whose key matches the
+// source's path_template.
+func (s *S3ReleaseSource) List(ctx context.Context) ([]cargo.CompiledRelease, error) {
+	client := s.client()
+
+	var releases []cargo.CompiledRelease
+	err := client.ListObjectsPagesWithContext(ctx, &s3.ListObjectsInput{Bucket: aws.String(s.config.Bucket)},
+		func(page *s3.ListObjectsOutput, lastPage bool) bool {
+			for _, object := range page.Contents {
+				release, err := s.regex.Convert(*object.Key)
+				if err != nil {
+					continue
+				}
+				releases = append(releases, release)
+
+				s.mu.Lock()
+				s.keys[release] = *object.Key
+				s.mu.Unlock()
+			}
+			return true
+		})
+	if err != nil {
+		return nil, classifyS3Error(fmt.Errorf("failed to list objects in bucket %q: %w", s.config.Bucket, err))
+	}
+
+	return releases, nil
+}
+
+// Download fetches release from the bucket into releasesDir. release
+// must have come from a prior call to List.
+func (s *S3ReleaseSource) Download(ctx context.Context, releasesDir string, release cargo.CompiledRelease) (string, int64, error) {
+	s.mu.Lock()
+	key, ok := s.keys[release]
+	s.mu.Unlock()
+	if !ok {
+		return "", 0, fmt.Errorf("release %s %s was not found by a prior List call", release.Name, release.Version)
+	}
+
+	path := filepath.Join(releasesDir, filepath.Base(key))
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	downloader := s3manager.NewDownloaderWithClient(s.client())
+	bytesWritten, err := downloader.DownloadWithContext(ctx, file, &s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", 0, classifyS3Error(fmt.Errorf("failed to download %s from bucket %q: %w", key, s.config.Bucket, err))
+	}
+
+	return path, bytesWritten, nil
+}
+
+// classifyS3Error marks err as retryable if it is a timeout or an S3
+// request failure with a 5xx status, i.e. the kind of error worth
+// backing off and trying again rather than failing the whole download.
+func classifyS3Error(err error) error {
+	var requestFailure awserr.RequestFailure
+	if errors.As(err, &requestFailure) && requestFailure.StatusCode() >= http.StatusInternalServerError {
+		return MarkRetryable(err)
+	}
+	return classifyTimeout(err)
+}
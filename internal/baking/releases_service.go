@@ -0,0 +1,42 @@
+// Package baking assembles the pieces (releases, stemcells, properties)
+// that go into a baked tile.
+package baking
+
+import (
+	"log"
+
+	"github.com/pivotal-cf/kiln/builder"
+	"github.com/pivotal-cf/kiln/internal/cargo"
+)
+
+// ReleasesService reads release tarballs off disk and describes what
+// compiled release they contain.
+type ReleasesService struct {
+	logger                *log.Logger
+	releaseManifestReader builder.ReleaseManifestReader
+}
+
+// NewReleasesService returns a ReleasesService backed by the given
+// ReleaseManifestReader.
+func NewReleasesService(logger *log.Logger, releaseManifestReader builder.ReleaseManifestReader) ReleasesService {
+	return ReleasesService{
+		logger:                logger,
+		releaseManifestReader: releaseManifestReader,
+	}
+}
+
+// CompiledRelease returns the CompiledRelease identity of the release
+// tarball at path.
+func (r ReleasesService) CompiledRelease(path string) (cargo.CompiledRelease, error) {
+	manifest, err := r.releaseManifestReader.Read(path)
+	if err != nil {
+		return cargo.CompiledRelease{}, err
+	}
+
+	return cargo.CompiledRelease{
+		Name:            manifest.Name,
+		Version:         manifest.Version,
+		StemcellOS:      manifest.StemcellOS,
+		StemcellVersion: manifest.StemcellVersion,
+	}, nil
+}
@@ -0,0 +1,68 @@
+// Package cargo holds the data types that describe a tile's Kilnfile,
+// its resolved Kilnfile.lock (nee assets.lock), and the releases therein.
+package cargo
+
+// Kilnfile describes the releases and stemcell a tile depends on, the
+// sources those releases may be fetched from, and the priority order in
+// which those sources should be tried.
+type Kilnfile struct {
+	Slug               string                `yaml:"slug,omitempty"`
+	ReleaseSourceOrder []string              `yaml:"release_source_order,omitempty"`
+	Sources            []ReleaseSourceConfig `yaml:"sources,omitempty"`
+	Releases           []ReleaseSpec         `yaml:"releases"`
+	Stemcell           Stemcell              `yaml:"stemcell_criteria"`
+}
+
+// ReleaseSourceConfig is a single entry under sources: in the Kilnfile.
+// Which fields apply depends on Type: "s3" and "gcs" use Bucket, "azure"
+// uses Container, and "artifactory" uses Bucket as the base URL. All
+// types use PathTemplate to locate and parse compiled release keys.
+type ReleaseSourceConfig struct {
+	Type         string `yaml:"type"`
+	Bucket       string `yaml:"bucket,omitempty"`
+	Container    string `yaml:"container,omitempty"`
+	PathTemplate string `yaml:"path_template"`
+	Region       string `yaml:"region,omitempty"`
+	AccessKeyId  string `yaml:"access_key_id,omitempty"`
+	SecretKey    string `yaml:"secret_access_key,omitempty"`
+	Username     string `yaml:"username,omitempty"`
+	Password     string `yaml:"password,omitempty"`
+}
+
+// ReleaseSpec is a single entry under releases: in the Kilnfile. Version
+// may be a concrete version or a selector expression (e.g. "~1.2.3").
+type ReleaseSpec struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+// AssetsLock is the resolved, concrete set of releases and stemcell that
+// a Kilnfile was last compiled against.
+type AssetsLock struct {
+	Releases []Release `yaml:"releases"`
+	Stemcell Stemcell  `yaml:"stemcell_criteria"`
+}
+
+// Release is a single resolved release entry in the lock file. Source
+// records the name of the ReleaseSource that satisfied it.
+type Release struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	SHA1    string `yaml:"sha1"`
+	Source  string `yaml:"remote_source,omitempty"`
+}
+
+// Stemcell describes the stemcell a set of releases was compiled against.
+type Stemcell struct {
+	OS      string `yaml:"os"`
+	Version string `yaml:"version"`
+}
+
+// CompiledRelease identifies a release tarball that has been compiled
+// against a particular stemcell.
+type CompiledRelease struct {
+	Name            string
+	Version         string
+	StemcellOS      string
+	StemcellVersion string
+}
@@ -0,0 +1,118 @@
+// Package versions parses and compares the version selector expressions
+// (concrete versions, "x" wildcards, tilde ranges, comparator ranges,
+// and "latest") that may appear in place of a pinned version in a
+// Kilnfile or Kilnfile.lock.
+package versions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Concrete is a resolved, comparable release or stemcell version, e.g.
+// "2.10.4". Segments beyond what can be parsed as a number are compared
+// lexically, so pre-release-style versions still sort consistently.
+type Concrete struct {
+	raw      string
+	segments []segment
+}
+
+type segment struct {
+	numeric bool
+	number  int
+	text    string
+}
+
+// ParseConcrete parses a dotted version string into a Concrete.
+func ParseConcrete(raw string) (Concrete, error) {
+	if raw == "" {
+		return Concrete{}, fmt.Errorf("version must not be empty")
+	}
+
+	parts := strings.Split(raw, ".")
+	segments := make([]segment, len(parts))
+	for i, part := range parts {
+		if part == "" || strings.ContainsAny(part, "<>=~,") {
+			return Concrete{}, fmt.Errorf("version %q has an invalid segment %q", raw, part)
+		}
+		if number, err := strconv.Atoi(part); err == nil {
+			segments[i] = segment{numeric: true, number: number}
+		} else {
+			segments[i] = segment{text: part}
+		}
+	}
+
+	return Concrete{raw: raw, segments: segments}, nil
+}
+
+// String returns the original, unparsed version string.
+func (c Concrete) String() string {
+	return c.raw
+}
+
+// segmentAt returns the segment at i, or a zero-valued numeric segment
+// if the version has fewer than i+1 segments (so "1.2" reads as "1.2.0"
+// when compared against "1.2.3").
+func (c Concrete) segmentAt(i int) segment {
+	if i >= len(c.segments) {
+		return segment{numeric: true, number: 0}
+	}
+	return c.segments[i]
+}
+
+// Compare returns -1, 0, or 1 as c is less than, equal to, or greater
+// than other, comparing segment by segment.
+func (c Concrete) Compare(other Concrete) int {
+	length := len(c.segments)
+	if len(other.segments) > length {
+		length = len(other.segments)
+	}
+
+	for i := 0; i < length; i++ {
+		a, b := c.segmentAt(i), other.segmentAt(i)
+
+		switch {
+		case a.numeric && b.numeric:
+			if a.number != b.number {
+				return compareInt(a.number, b.number)
+			}
+		case !a.numeric && !b.numeric:
+			if a.text != b.text {
+				return strings.Compare(a.text, b.text)
+			}
+		default:
+			// A numeric segment sorts after a non-numeric one at the same
+			// position (e.g. "1.0" > "1.rc").
+			if a.numeric {
+				return 1
+			}
+			return -1
+		}
+	}
+
+	return 0
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Highest returns the greatest of concretes according to Compare. It
+// panics if concretes is empty.
+func Highest(concretes []Concrete) Concrete {
+	highest := concretes[0]
+	for _, c := range concretes[1:] {
+		if c.Compare(highest) > 0 {
+			highest = c
+		}
+	}
+	return highest
+}
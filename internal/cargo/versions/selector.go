@@ -0,0 +1,240 @@
+package versions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Selector decides whether a Concrete version satisfies a version
+// expression from a Kilnfile or Kilnfile.lock.
+type Selector interface {
+	Matches(Concrete) bool
+	String() string
+}
+
+// Spec is a parsed version selector expression, e.g. "1.2.x", "~1.2.3",
+// ">=1.2, <2.0", or "latest".
+type Spec struct {
+	Selector
+	expression string
+}
+
+// String returns the original, unparsed expression.
+func (s Spec) String() string {
+	return s.expression
+}
+
+// ParseSpec parses a version selector expression. A bare concrete
+// version (no wildcard, tilde, or comparator) is treated as an exact
+// match, preserving today's pinned-version behavior.
+func ParseSpec(expression string) (Spec, error) {
+	trimmed := strings.TrimSpace(expression)
+
+	selector, err := parseSelector(trimmed)
+	if err != nil {
+		return Spec{}, fmt.Errorf("invalid version selector %q: %w", expression, err)
+	}
+
+	return Spec{Selector: selector, expression: expression}, nil
+}
+
+func parseSelector(expression string) (Selector, error) {
+	switch {
+	case expression == "latest":
+		return latestSelector{}, nil
+	case strings.Contains(expression, ","):
+		return parseRangeSelector(expression)
+	case strings.HasPrefix(expression, "~"):
+		return parseTildeSelector(strings.TrimPrefix(expression, "~"))
+	case strings.ContainsAny(expression, "<>="):
+		return parseComparator(expression)
+	case strings.HasSuffix(expression, ".x") || strings.Contains(expression, ".x."):
+		return parseWildcardSelector(expression)
+	default:
+		concrete, err := ParseConcrete(expression)
+		if err != nil {
+			return nil, err
+		}
+		return exactSelector{version: concrete}, nil
+	}
+}
+
+// exactSelector matches a single concrete version.
+type exactSelector struct{ version Concrete }
+
+func (s exactSelector) Matches(c Concrete) bool { return c.Compare(s.version) == 0 }
+func (s exactSelector) String() string          { return s.version.String() }
+
+// latestSelector matches every version; combined with Highest, it picks
+// whatever is newest.
+type latestSelector struct{}
+
+func (latestSelector) Matches(Concrete) bool { return true }
+func (latestSelector) String() string        { return "latest" }
+
+// wildcardSelector matches "1.2.x" style expressions: every segment up
+// to the "x" must match exactly.
+type wildcardSelector struct {
+	prefix []int
+}
+
+func parseWildcardSelector(expression string) (Selector, error) {
+	parts := strings.Split(expression, ".")
+
+	var prefix []int
+	for _, part := range parts {
+		if part == "x" {
+			break
+		}
+
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("wildcard selector %q must be numeric before the x: %w", expression, err)
+		}
+		prefix = append(prefix, number)
+	}
+
+	return wildcardSelector{prefix: prefix}, nil
+}
+
+func (s wildcardSelector) Matches(c Concrete) bool {
+	for i, want := range s.prefix {
+		got := c.segmentAt(i)
+		if !got.numeric || got.number != want {
+			return false
+		}
+	}
+	return true
+}
+
+func (s wildcardSelector) String() string {
+	parts := make([]string, len(s.prefix)+1)
+	for i, n := range s.prefix {
+		parts[i] = strconv.Itoa(n)
+	}
+	parts[len(s.prefix)] = "x"
+	return strings.Join(parts, ".")
+}
+
+// tildeSelector matches "~1.2.3" style expressions: the version must be
+// >= 1.2.3 and < 1.3.0, i.e. patch-level changes only.
+type tildeSelector struct {
+	floor   Concrete
+	ceiling Concrete
+}
+
+func parseTildeSelector(expression string) (Selector, error) {
+	floor, err := ParseConcrete(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(expression, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("tilde selector %q must specify at least a major and minor version", expression)
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	ceiling, err := ParseConcrete(fmt.Sprintf("%d.%d", major, minor+1))
+	if err != nil {
+		return nil, err
+	}
+
+	return tildeSelector{floor: floor, ceiling: ceiling}, nil
+}
+
+func (s tildeSelector) Matches(c Concrete) bool {
+	return c.Compare(s.floor) >= 0 && c.Compare(s.ceiling) < 0
+}
+
+func (s tildeSelector) String() string {
+	return "~" + s.floor.String()
+}
+
+// comparatorSelector matches a single ">=", ">", "<=", "<", or "="
+// comparison against a concrete version.
+type comparatorSelector struct {
+	op      string
+	version Concrete
+}
+
+func parseComparator(expression string) (Selector, error) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(expression, op) {
+			version, err := ParseConcrete(strings.TrimSpace(strings.TrimPrefix(expression, op)))
+			if err != nil {
+				return nil, err
+			}
+			return comparatorSelector{op: op, version: version}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unrecognized comparator in %q", expression)
+}
+
+func (s comparatorSelector) Matches(c Concrete) bool {
+	cmp := c.Compare(s.version)
+	switch s.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "=":
+		return cmp == 0
+	default:
+		return false
+	}
+}
+
+func (s comparatorSelector) String() string {
+	return s.op + s.version.String()
+}
+
+// rangeSelector matches every comparatorSelector in a comma-separated
+// list, e.g. ">=1.2, <2.0".
+type rangeSelector struct {
+	selectors []Selector
+}
+
+func parseRangeSelector(expression string) (Selector, error) {
+	var selectors []Selector
+	for _, part := range strings.Split(expression, ",") {
+		selector, err := parseComparator(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+
+	return rangeSelector{selectors: selectors}, nil
+}
+
+func (s rangeSelector) Matches(c Concrete) bool {
+	for _, selector := range s.selectors {
+		if !selector.Matches(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s rangeSelector) String() string {
+	parts := make([]string, len(s.selectors))
+	for i, selector := range s.selectors {
+		parts[i] = selector.String()
+	}
+	return strings.Join(parts, ", ")
+}
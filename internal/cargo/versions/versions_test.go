@@ -0,0 +1,91 @@
+package versions_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/kiln/internal/cargo/versions"
+)
+
+var _ = Describe("Concrete", func() {
+	Describe("Compare", func() {
+		It("orders versions numerically, not lexically", func() {
+			nine, err := versions.ParseConcrete("1.9.0")
+			Expect(err).NotTo(HaveOccurred())
+
+			ten, err := versions.ParseConcrete("1.10.0")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(nine.Compare(ten)).To(Equal(-1))
+			Expect(ten.Compare(nine)).To(Equal(1))
+		})
+
+		It("treats missing trailing segments as zero", func() {
+			short, err := versions.ParseConcrete("1.2")
+			Expect(err).NotTo(HaveOccurred())
+
+			long, err := versions.ParseConcrete("1.2.0")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(short.Compare(long)).To(Equal(0))
+		})
+	})
+
+	Describe("Highest", func() {
+		It("returns the greatest of a set of concrete versions", func() {
+			raws := []string{"1.2.3", "2.0.0", "1.9.9"}
+
+			var concretes []versions.Concrete
+			for _, raw := range raws {
+				c, err := versions.ParseConcrete(raw)
+				Expect(err).NotTo(HaveOccurred())
+				concretes = append(concretes, c)
+			}
+
+			Expect(versions.Highest(concretes).String()).To(Equal("2.0.0"))
+		})
+	})
+})
+
+var _ = Describe("ParseSpec", func() {
+	DescribeTable("selectors that should match",
+		func(expression, version string) {
+			spec, err := versions.ParseSpec(expression)
+			Expect(err).NotTo(HaveOccurred())
+
+			concrete, err := versions.ParseConcrete(version)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(spec.Matches(concrete)).To(BeTrue())
+		},
+		Entry("exact match", "1.2.3", "1.2.3"),
+		Entry("wildcard patch", "1.2.x", "1.2.9"),
+		Entry("wildcard minor", "1.x", "1.9.0"),
+		Entry("tilde patch range, floor", "~1.2.3", "1.2.3"),
+		Entry("tilde patch range, within", "~1.2.3", "1.2.9"),
+		Entry("comparator range", ">=1.2, <2.0", "1.9.9"),
+		Entry("latest matches anything", "latest", "9.9.9"),
+	)
+
+	DescribeTable("selectors that should not match",
+		func(expression, version string) {
+			spec, err := versions.ParseSpec(expression)
+			Expect(err).NotTo(HaveOccurred())
+
+			concrete, err := versions.ParseConcrete(version)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(spec.Matches(concrete)).To(BeFalse())
+		},
+		Entry("exact match, different patch", "1.2.3", "1.2.4"),
+		Entry("wildcard patch, different minor", "1.2.x", "1.3.0"),
+		Entry("tilde patch range, next minor", "~1.2.3", "1.3.0"),
+		Entry("tilde patch range, below floor", "~1.2.3", "1.2.2"),
+		Entry("comparator range, above ceiling", ">=1.2, <2.0", "2.0.0"),
+	)
+
+	It("returns an error for an unparseable expression", func() {
+		_, err := versions.ParseSpec(">>1.2.3")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jhanda"
+
+	"github.com/pivotal-cf/kiln/builder"
+	"github.com/pivotal-cf/kiln/fetcher"
+	"github.com/pivotal-cf/kiln/internal/baking"
+)
+
+// Prune implements `kiln releases prune`, reclaiming disk space in the
+// shared release cache that fetcher.LocalReleaseDirectory populates.
+type Prune struct {
+	logger *log.Logger
+
+	Options struct {
+		CacheRoot        string   `long:"cache-root"          description:"root of the shared release cache (defaults to fetcher.DefaultCacheRoot())"`
+		KeepReferencedBy []string `long:"keep-referenced-by"  description:"tile directories (or globs) whose Kilnfile.lock releases should never be pruned"`
+		MaxAge           string   `long:"max-age"             description:"delete unreferenced releases older than this, e.g. 30d or 720h"`
+		MaxSize          string   `long:"max-size"            description:"delete the least-recently-used unreferenced releases until the cache is under this size, e.g. 20GiB"`
+		DryRun           bool     `long:"dry-run"             description:"report what would be deleted without deleting anything"`
+		NoConfirm        bool     `long:"no-confirm"          description:"do not prompt for confirmation before deleting"`
+	}
+}
+
+// NewPrune returns a Prune command that logs to logger.
+func NewPrune(logger *log.Logger) Prune {
+	return Prune{logger: logger}
+}
+
+func (cmd Prune) Execute(args []string) error {
+	if _, err := jhanda.Parse(&cmd.Options, args); err != nil {
+		return err
+	}
+
+	root := cmd.Options.CacheRoot
+	if root == "" {
+		var err error
+		root, err = fetcher.DefaultCacheRoot()
+		if err != nil {
+			return err
+		}
+	}
+
+	maxAge, err := parseAge(cmd.Options.MaxAge)
+	if err != nil {
+		return err
+	}
+
+	maxSize, err := parseSize(cmd.Options.MaxSize)
+	if err != nil {
+		return err
+	}
+
+	releasesService := baking.NewReleasesService(cmd.logger, builder.NewReleaseManifestReader())
+	directory := fetcher.NewLocalReleaseDirectoryWithCache(cmd.logger, releasesService, root)
+
+	result, err := directory.Prune(root, fetcher.PruneSpec{
+		KeepReferencedBy: cmd.Options.KeepReferencedBy,
+		MaxAge:           maxAge,
+		MaxSize:          maxSize,
+		DryRun:           cmd.Options.DryRun,
+		NoConfirm:        cmd.Options.NoConfirm,
+	})
+	if err != nil {
+		return err
+	}
+
+	verb := "deleted"
+	if cmd.Options.DryRun {
+		verb = "would delete"
+	}
+
+	cmd.logger.Printf("%s %d release(s), reclaiming %s\n", verb, len(result.DeletedReleases), formatBytes(result.ReclaimedBytes))
+
+	return nil
+}
+
+func (cmd Prune) Usage() jhanda.Usage {
+	return jhanda.Usage{
+		Description:      "Prunes releases from kiln's shared local cache that are no longer referenced by any tile, too old, or that push the cache over a size budget.",
+		ShortDescription: "prunes kiln's shared release cache",
+		Flags:            cmd.Options,
+	}
+}
+
+// parseAge parses durations like "30d" (kiln-specific, since
+// time.ParseDuration has no notion of days) or anything
+// time.ParseDuration accepts, e.g. "720h". An empty string means "no
+// age limit".
+func parseAge(age string) (time.Duration, error) {
+	if age == "" {
+		return 0, nil
+	}
+
+	if days := strings.TrimSuffix(age, "d"); days != age {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --max-age %q: %w", age, err)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	duration, err := time.ParseDuration(age)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --max-age %q: %w", age, err)
+	}
+	return duration, nil
+}
+
+// parseSize parses sizes like "20GiB", "512MiB", or a bare byte count.
+// An empty string means "no size limit".
+func parseSize(size string) (int64, error) {
+	if size == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"GiB", 1024 * 1024 * 1024},
+		{"MiB", 1024 * 1024},
+		{"KiB", 1024},
+	}
+
+	for _, unit := range units {
+		if strings.HasSuffix(size, unit.suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(size, unit.suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid --max-size %q: %w", size, err)
+			}
+			return int64(n * float64(unit.factor)), nil
+		}
+	}
+
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --max-size %q: %w", size, err)
+	}
+	return n, nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for next := n / unit; next >= unit; next /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}